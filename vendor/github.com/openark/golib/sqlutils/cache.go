@@ -0,0 +1,193 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"expvar"
+	"hash/fnv"
+	"sync"
+)
+
+// defaultTranslationCacheSize is the bound on the number of distinct
+// (dialect, statement) translations memoized at once. Orchestrator issues
+// the same handful of INSERT/UPDATE statements millions of times per day, so
+// a small cache eliminates almost all repeat regex/AST work.
+const defaultTranslationCacheSize = 4096
+
+// translationCacheShardCount splits the cache into independently-locked
+// shards, keyed by a hash of the cache key, so concurrent translations of
+// different statements don't serialize on one mutex.
+const translationCacheShardCount = 16
+
+var translationsTotal = expvar.NewMap("sqlutils_translations_total")
+
+// translationCacheShard is a bounded LRU over one slice of the keyspace.
+type translationCacheShard struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type translationCacheEntry struct {
+	key   string
+	value string
+}
+
+func newTranslationCacheShard(capacity int) *translationCacheShard {
+	return &translationCacheShard{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (s *translationCacheShard) get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return "", false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*translationCacheEntry).value, true
+}
+
+func (s *translationCacheShard) put(key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capacity <= 0 {
+		return
+	}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*translationCacheEntry).value = value
+		s.order.MoveToFront(elem)
+		return
+	}
+	elem := s.order.PushFront(&translationCacheEntry{key: key, value: value})
+	s.entries[key] = elem
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		if oldest == nil {
+			break
+		}
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*translationCacheEntry).key)
+	}
+}
+
+func (s *translationCacheShard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// translationCache is a bounded, thread-safe LRU cache mapping
+// sha1(dialect|statement) to its translated form, sharded by key hash so
+// that a hit in one shard never blocks a concurrent hit or miss in another.
+// The compiled regexes and the translation output are both immutable once
+// produced, so a shard's lock is only ever held for an O(1) map/list update.
+type translationCache struct {
+	capacity int
+	shards   [translationCacheShardCount]*translationCacheShard
+}
+
+func newTranslationCache(capacity int) *translationCache {
+	perShard := capacity / translationCacheShardCount
+	if capacity > 0 && perShard == 0 {
+		perShard = 1
+	}
+	c := &translationCache{capacity: capacity}
+	for i := range c.shards {
+		c.shards[i] = newTranslationCacheShard(perShard)
+	}
+	return c
+}
+
+func (c *translationCache) shardFor(key string) *translationCacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%translationCacheShardCount]
+}
+
+func (c *translationCache) get(key string) (string, bool) {
+	return c.shardFor(key).get(key)
+}
+
+func (c *translationCache) put(key string, value string) {
+	c.shardFor(key).put(key, value)
+}
+
+func (c *translationCache) len() int {
+	total := 0
+	for _, shard := range c.shards {
+		total += shard.len()
+	}
+	return total
+}
+
+var activeTranslationCache = newTranslationCache(defaultTranslationCacheSize)
+var activeTranslationCacheMu sync.Mutex
+
+// SetTranslationCacheSize resizes (and clears) the dialect translation cache
+// shared by ToSqlite3Dialect and ToPostgresDialect. Passing 0 disables
+// caching entirely.
+func SetTranslationCacheSize(n int) {
+	activeTranslationCacheMu.Lock()
+	defer activeTranslationCacheMu.Unlock()
+	activeTranslationCache = newTranslationCache(n)
+}
+
+func translationCacheKey(dialect Dialect, statement string) string {
+	h := sha1.New()
+	switch dialect {
+	case DialectPostgres:
+		h.Write([]byte("postgres|"))
+	case DialectMySQL:
+		h.Write([]byte("mysql|"))
+	default:
+		h.Write([]byte("sqlite3|"))
+	}
+	h.Write([]byte(statement))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedTranslate memoizes translate(statement) in the shared LRU cache,
+// keyed by sha1(dialect|statement).
+func cachedTranslate(dialect Dialect, statement string, translate func(string) string) string {
+	activeTranslationCacheMu.Lock()
+	cache := activeTranslationCache
+	activeTranslationCacheMu.Unlock()
+
+	if cache.capacity <= 0 {
+		translationsTotal.Add("false", 1)
+		return translate(statement)
+	}
+
+	key := translationCacheKey(dialect, statement)
+	if value, ok := cache.get(key); ok {
+		translationsTotal.Add("true", 1)
+		return value
+	}
+	translationsTotal.Add("false", 1)
+	value := translate(statement)
+	cache.put(key, value)
+	return value
+}
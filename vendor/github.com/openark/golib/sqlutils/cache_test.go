@@ -0,0 +1,100 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTranslationCacheHitsReturnSameValue(t *testing.T) {
+	SetTranslationCacheSize(defaultTranslationCacheSize)
+	defer SetTranslationCacheSize(defaultTranslationCacheSize)
+
+	statement := "insert into t (a) values (?)"
+	first := ToSqlite3Dialect(statement)
+	second := ToSqlite3Dialect(statement)
+	if first != second {
+		t.Fatalf("expected cached translation to match, got %q vs %q", first, second)
+	}
+}
+
+func TestTranslationCacheEvictsBeyondCapacity(t *testing.T) {
+	const capacity = translationCacheShardCount // 1 entry per shard
+	SetTranslationCacheSize(capacity)
+	defer SetTranslationCacheSize(defaultTranslationCacheSize)
+
+	for i := 0; i < capacity*4; i++ {
+		ToSqlite3Dialect(fmt.Sprintf("insert into t (a) values (%d)", i))
+	}
+
+	if got := activeTranslationCache.len(); got > capacity {
+		t.Fatalf("expected cache to stay within capacity %d, has %d entries", capacity, got)
+	}
+}
+
+func TestSetTranslationCacheSizeZeroDisablesCaching(t *testing.T) {
+	SetTranslationCacheSize(0)
+	defer SetTranslationCacheSize(defaultTranslationCacheSize)
+
+	ToSqlite3Dialect("insert into t (a) values (1)")
+	if got := activeTranslationCache.len(); got != 0 {
+		t.Fatalf("expected no entries cached when size is 0, got %d", got)
+	}
+}
+
+var benchmarkStatements = []string{
+	"insert into database_instance (hostname, port, last_checked) values (?, ?, now())",
+	"insert ignore into database_instance_topology_history (hostname, port, snapshot_unix_timestamp, master_host) values (?, ?, unix_timestamp(), ?)",
+	"update database_instance set last_seen = now() where hostname = ? and port = ?",
+	"insert into database_instance_maintenance (hostname, port, begin_timestamp) values (?, ?, now()) on duplicate key update begin_timestamp = values(begin_timestamp)",
+	"select hostname, port from database_instance where last_checked < now() - interval ? minute",
+	"create table database_instance (hostname varchar(128) not null, port smallint not null, primary key (hostname, port)) engine=innodb",
+	"alter table database_instance add column data_center varchar(32) not null after port",
+	"insert into audit (audit_type, message, created_at) values (?, ?, now())",
+	"select count(*) from topology_recovery where start_active_period > now() - interval 1 hour",
+	"insert ignore into hostname_resolve (hostname, resolved_hostname) values (?, ?)",
+	"update topology_failure_detection set successor_hostname = ?, successor_port = ? where detection_id = ?",
+	"insert into candidate_database_instance (hostname, port, last_suggested) values (?, ?, now()) on duplicate key update last_suggested = values(last_suggested)",
+	"select * from database_instance where cast(version as signed) >= ?",
+	"delete from database_instance_downtime where end_timestamp < now() - interval 7 day",
+	"insert into node_health (hostname, token, last_seen_active) values (?, ?, now()) on duplicate key update last_seen_active = values(last_seen_active)",
+	"select concat(hostname, ':', port) as instance_key from database_instance",
+	"alter table database_instance add index ix_last_checked (last_checked)",
+	"select * from database_instance where hostname rlike ?",
+	"insert into database_instance_topology_history (hostname, port) values (?, ?)",
+	"update cluster_alias set alias = ? where cluster_name = ?",
+}
+
+func BenchmarkToSqlite3DialectUncached(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		toSqlite3DialectUncached(benchmarkStatements[i%len(benchmarkStatements)])
+	}
+}
+
+func BenchmarkToSqlite3DialectCached(b *testing.B) {
+	SetTranslationCacheSize(defaultTranslationCacheSize)
+	defer SetTranslationCacheSize(defaultTranslationCacheSize)
+	for _, statement := range benchmarkStatements {
+		ToSqlite3Dialect(statement)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToSqlite3Dialect(benchmarkStatements[i%len(benchmarkStatements)])
+	}
+}
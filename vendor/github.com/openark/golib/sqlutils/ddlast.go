@@ -0,0 +1,95 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"fmt"
+)
+
+// ColumnDef is a single column (or column-like constraint) appearing in a
+// CREATE TABLE column list, or the target of an ALTER TABLE ADD/CHANGE COLUMN.
+type ColumnDef struct {
+	Name       string
+	Definition string // everything after the column name, verbatim (type + constraints)
+}
+
+// CreateTable is the AST node for a `CREATE TABLE ... (...) [options]` statement.
+type CreateTable struct {
+	Name    string
+	Columns []ColumnDef
+	Options string
+}
+
+// AlterAction is one clause of a (possibly multi-clause) ALTER TABLE statement.
+type AlterAction interface {
+	isAlterAction()
+}
+
+type AddColumn struct {
+	Column ColumnDef
+	After  string // empty if not positioned
+}
+
+type AddIndex struct {
+	Name    string
+	Unique  bool
+	Columns string // verbatim parenthesized column list, e.g. "(a, b)"
+}
+
+type DropIndex struct {
+	Name string
+}
+
+type ChangeColumn struct {
+	OldName string
+	New     ColumnDef
+}
+
+type RenameTable struct {
+	NewName string
+}
+
+func (AddColumn) isAlterAction()    {}
+func (AddIndex) isAlterAction()     {}
+func (DropIndex) isAlterAction()    {}
+func (ChangeColumn) isAlterAction() {}
+func (RenameTable) isAlterAction()  {}
+
+// AlterTable is the AST node for an `ALTER TABLE <name> <action>[, <action>...]` statement.
+type AlterTable struct {
+	Name    string
+	Actions []AlterAction
+}
+
+// Statement is any DDL node the parser can produce.
+type Statement interface {
+	isStatement()
+}
+
+func (CreateTable) isStatement() {}
+func (AlterTable) isStatement()  {}
+
+// ParseError indicates the DDL parser did not recognize the statement shape;
+// callers fall back to the legacy regex pipeline in that case.
+type ParseError struct {
+	Statement string
+	Reason    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("sqlutils: cannot parse statement as DDL (%s): %s", e.Reason, e.Statement)
+}
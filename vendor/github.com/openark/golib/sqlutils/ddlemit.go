@@ -0,0 +1,77 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmitSqlite3CreateTable renders a parsed CreateTable as sqlite3-dialect DDL,
+// applying the column-safe subset of the regex pipeline's type rewrites
+// (columnTypeConversions) to each column's own text rather than the raw
+// statement -- so a value like `'engine=innodb'` inside a string literal is
+// never mistaken for the `ENGINE=innodb` table option, which is instead
+// stripped from t.Options alone via the full conversion set.
+func EmitSqlite3CreateTable(t *CreateTable) string {
+	cols := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		def := applyConversions(c.Name+" "+c.Definition, columnTypeConversions)
+		cols = append(cols, strings.TrimSpace(def))
+	}
+	options := strings.TrimSpace(applyConversions(t.Options, createTableConversions))
+	stmt := fmt.Sprintf("create table %s (%s)", quoteSqlite3Ident(t.Name), strings.Join(cols, ", "))
+	if options != "" {
+		stmt += " " + options
+	}
+	return stmt
+}
+
+// EmitSqlite3AlterTable renders a parsed AlterTable as one or more
+// semicolon-joined sqlite3 statements.
+func EmitSqlite3AlterTable(t *AlterTable) string {
+	var stmts []string
+	for _, action := range t.Actions {
+		switch a := action.(type) {
+		case AddColumn:
+			// Run conversions over the full "add column ..." clause, not just
+			// the column definition, so the addColumnDefaultConversions rules
+			// (anchored to the end of the clause) still match.
+			clause := fmt.Sprintf("add column %s %s", a.Column.Name, a.Column.Definition)
+			clause = applyConversions(clause, columnTypeConversions)
+			clause = strings.TrimSpace(applyConversions(clause, addColumnDefaultConversions))
+			stmts = append(stmts, fmt.Sprintf("alter table %s %s", quoteSqlite3Ident(t.Name), clause))
+		case AddIndex:
+			unique := ""
+			if a.Unique {
+				unique = "unique "
+			}
+			table := quoteSqlite3Ident(t.Name)
+			stmts = append(stmts, fmt.Sprintf("create %sindex %s_%s on %s %s", unique, a.Name, t.Name, table, a.Columns))
+		case DropIndex:
+			stmts = append(stmts, fmt.Sprintf("drop index %s", a.Name))
+		case ChangeColumn:
+			// sqlite3 only supports renaming columns in-place; a type/constraint
+			// change requires a table rebuild the caller must handle separately.
+			stmts = append(stmts, fmt.Sprintf("alter table %s rename column %s to %s",
+				quoteSqlite3Ident(t.Name), quoteSqlite3Ident(a.OldName), quoteSqlite3Ident(a.New.Name)))
+		case RenameTable:
+			stmts = append(stmts, fmt.Sprintf("alter table %s rename to %s", quoteSqlite3Ident(t.Name), quoteSqlite3Ident(a.NewName)))
+		}
+	}
+	return strings.Join(stmts, "; ")
+}
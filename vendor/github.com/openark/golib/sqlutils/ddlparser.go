@@ -0,0 +1,345 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"strings"
+)
+
+// stripComments removes `-- ...` line comments and `/* ... */` block comments,
+// while leaving quoted/backticked literals (which may themselves contain
+// comment-like substrings) untouched.
+func stripComments(statement string) string {
+	var out strings.Builder
+	runes := []rune(statement)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := skipQuoted(runes, i)
+			out.WriteString(string(runes[i:j]))
+			i = j
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		default:
+			out.WriteRune(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// skipQuoted returns the index just past the quoted/backticked token starting
+// at runes[start] (which must be a quote character), honoring doubled-quote
+// escaping (e.g. 'it''s').
+func skipQuoted(runes []rune, start int) int {
+	quote := runes[start]
+	i := start + 1
+	for i < len(runes) {
+		if runes[i] == quote {
+			if i+1 < len(runes) && runes[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+// splitTopLevel splits s on commas that are not nested inside parens or
+// quoted/backticked literals, e.g. so `a enum('x,y','z'), b int` yields two parts.
+func splitTopLevel(s string) []string {
+	var parts []string
+	runes := []rune(s)
+	depth := 0
+	last := 0
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(runes, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(string(runes[last:i])))
+			last = i + 1
+		}
+		i++
+	}
+	parts = append(parts, strings.TrimSpace(string(runes[last:])))
+	return parts
+}
+
+// findMatchingParen returns the index of the ')' matching the '(' at runes[open].
+func findMatchingParen(runes []rune, open int) int {
+	depth := 0
+	i := open
+	for i < len(runes) {
+		c := runes[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(runes, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+func unquoteIdent(token string) string {
+	token = strings.TrimSpace(token)
+	if len(token) >= 2 && token[0] == '`' && token[len(token)-1] == '`' {
+		return token[1 : len(token)-1]
+	}
+	return token
+}
+
+// identNeedsQuoting reports whether ident can only round-trip as a bare,
+// unquoted SQL identifier if it happens to look like one already -- i.e. it
+// must be re-quoted on emit if it contains anything but letters, digits and
+// underscores, or starts with a digit.
+func identNeedsQuoting(ident string) bool {
+	if ident == "" {
+		return true
+	}
+	for i, r := range ident {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			continue
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// quoteSqlite3Ident re-quotes ident with backticks if it needs quoting to
+// round-trip (e.g. it contains a space), matching how the legacy regex
+// pipeline passed already-backtick-quoted identifiers straight through.
+func quoteSqlite3Ident(ident string) string {
+	if !identNeedsQuoting(ident) {
+		return ident
+	}
+	return "`" + ident + "`"
+}
+
+// quotePostgresIdent re-quotes ident with double quotes if it needs quoting
+// to round-trip.
+func quotePostgresIdent(ident string) string {
+	if !identNeedsQuoting(ident) {
+		return ident
+	}
+	return `"` + ident + `"`
+}
+
+// firstToken splits off the leading whitespace-delimited token of s (an
+// identifier, which may be backtick-quoted) and returns it plus the remainder.
+func firstToken(s string) (token string, rest string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+	if s[0] == '`' {
+		runes := []rune(s)
+		end := skipQuoted(runes, 0)
+		return string(runes[:end]), strings.TrimSpace(string(runes[end:]))
+	}
+	idx := strings.IndexAny(s, " \t\n")
+	if idx < 0 {
+		return s, ""
+	}
+	return s[:idx], strings.TrimSpace(s[idx:])
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// ParseCreateTable parses a `CREATE TABLE` statement into a CreateTable AST
+// node. It returns a *ParseError (see errors.Is-style check via type assertion)
+// when the statement doesn't match the expected shape, in which case callers
+// should fall back to the regex-based pipeline.
+func ParseCreateTable(statement string) (*CreateTable, error) {
+	clean := stripComments(statement)
+	trimmed := strings.TrimSpace(clean)
+	if !hasPrefixFold(trimmed, "create table") {
+		return nil, &ParseError{statement, "not a CREATE TABLE"}
+	}
+	rest := strings.TrimSpace(trimmed[len("create table"):])
+	if hasPrefixFold(rest, "if not exists") {
+		rest = strings.TrimSpace(rest[len("if not exists"):])
+	}
+	name, rest := firstToken(rest)
+	runes := []rune(rest)
+	open := strings.IndexRune(rest, '(')
+	if open < 0 {
+		return nil, &ParseError{statement, "missing column list"}
+	}
+	closeAt := findMatchingParen(runes, open)
+	if closeAt < 0 {
+		return nil, &ParseError{statement, "unbalanced parens in column list"}
+	}
+	body := string(runes[open+1 : closeAt])
+	options := strings.TrimSpace(string(runes[closeAt+1:]))
+
+	table := &CreateTable{Name: unquoteIdent(name), Options: options}
+	for _, part := range splitTopLevel(body) {
+		if part == "" {
+			continue
+		}
+		colName, def := firstToken(part)
+		table.Columns = append(table.Columns, ColumnDef{Name: unquoteIdent(colName), Definition: def})
+	}
+	return table, nil
+}
+
+// ParseAlterTable parses an `ALTER TABLE` statement (possibly with multiple
+// comma-separated clauses) into an AlterTable AST node.
+func ParseAlterTable(statement string) (*AlterTable, error) {
+	clean := stripComments(statement)
+	trimmed := strings.TrimSpace(clean)
+	if !hasPrefixFold(trimmed, "alter table") {
+		return nil, &ParseError{statement, "not an ALTER TABLE"}
+	}
+	rest := strings.TrimSpace(trimmed[len("alter table"):])
+	name, rest := firstToken(rest)
+	alter := &AlterTable{Name: unquoteIdent(name)}
+
+	for _, clause := range splitTopLevel(rest) {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		action, err := parseAlterClause(clause)
+		if err != nil {
+			return nil, err
+		}
+		alter.Actions = append(alter.Actions, action)
+	}
+	if len(alter.Actions) == 0 {
+		return nil, &ParseError{statement, "no recognizable ALTER clause"}
+	}
+	return alter, nil
+}
+
+func parseAlterClause(clause string) (AlterAction, error) {
+	switch {
+	case hasPrefixFold(clause, "add column"):
+		return parseAddColumn(strings.TrimSpace(clause[len("add column"):]))
+	case hasPrefixFold(clause, "add index") || hasPrefixFold(clause, "add key"):
+		return parseAddIndex(clause, false)
+	case hasPrefixFold(clause, "add unique index") || hasPrefixFold(clause, "add unique key"):
+		return parseAddIndex(clause, true)
+	case hasPrefixFold(clause, "drop index") || hasPrefixFold(clause, "drop key"):
+		var body string
+		if hasPrefixFold(clause, "drop index") {
+			body = clause[len("drop index"):]
+		} else {
+			body = clause[len("drop key"):]
+		}
+		name, _ := firstToken(body)
+		return DropIndex{Name: unquoteIdent(name)}, nil
+	case hasPrefixFold(clause, "change column"):
+		return parseChangeColumn(strings.TrimSpace(clause[len("change column"):]))
+	case hasPrefixFold(clause, "change"):
+		return parseChangeColumn(strings.TrimSpace(clause[len("change"):]))
+	case hasPrefixFold(clause, "rename to"):
+		name, _ := firstToken(clause[len("rename to"):])
+		return RenameTable{NewName: unquoteIdent(name)}, nil
+	case hasPrefixFold(clause, "rename"):
+		name, _ := firstToken(clause[len("rename"):])
+		return RenameTable{NewName: unquoteIdent(name)}, nil
+	case hasPrefixFold(clause, "add"):
+		// bare "add <column> <def>" (no ADD COLUMN keyword)
+		return parseAddColumn(strings.TrimSpace(clause[len("add"):]))
+	}
+	return nil, &ParseError{clause, "unrecognized ALTER TABLE clause"}
+}
+
+func parseAddColumn(rest string) (AlterAction, error) {
+	lower := strings.ToLower(rest)
+	after := ""
+	if idx := strings.LastIndex(lower, " after "); idx >= 0 {
+		after, _ = firstToken(rest[idx+len(" after "):])
+		after = unquoteIdent(after)
+		rest = strings.TrimSpace(rest[:idx])
+	}
+	name, def := firstToken(rest)
+	if name == "" {
+		return nil, &ParseError{rest, "missing column name in ADD COLUMN"}
+	}
+	return AddColumn{Column: ColumnDef{Name: unquoteIdent(name), Definition: def}, After: after}, nil
+}
+
+func parseAddIndex(clause string, unique bool) (AlterAction, error) {
+	body := clause
+	for _, kw := range []string{"add unique index", "add unique key", "add index", "add key"} {
+		if hasPrefixFold(body, kw) {
+			body = strings.TrimSpace(body[len(kw):])
+			break
+		}
+	}
+	name, rest := firstToken(body)
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") {
+		return nil, &ParseError{clause, "missing column list in ADD INDEX"}
+	}
+	runes := []rune(rest)
+	closeAt := findMatchingParen(runes, 0)
+	if closeAt < 0 {
+		return nil, &ParseError{clause, "unbalanced parens in ADD INDEX"}
+	}
+	return AddIndex{Name: unquoteIdent(name), Unique: unique, Columns: string(runes[:closeAt+1])}, nil
+}
+
+func parseChangeColumn(rest string) (AlterAction, error) {
+	oldName, rest := firstToken(rest)
+	newName, def := firstToken(rest)
+	if oldName == "" || newName == "" {
+		return nil, &ParseError{rest, "missing column names in CHANGE COLUMN"}
+	}
+	return ChangeColumn{
+		OldName: unquoteIdent(oldName),
+		New:     ColumnDef{Name: unquoteIdent(newName), Definition: def},
+	}, nil
+}
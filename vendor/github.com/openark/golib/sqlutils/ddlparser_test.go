@@ -0,0 +1,205 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCreateTableStringLiteralNotMistakenForOption(t *testing.T) {
+	// a comment-like string value must not be stripped as if it were an ENGINE= option.
+	statement := "create table t (id int, note varchar(32) default 'engine=innodb')"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if len(table.Columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(table.Columns))
+	}
+	if !strings.Contains(table.Columns[1].Definition, "engine=innodb") {
+		t.Fatalf("expected literal to survive unmodified, got: %q", table.Columns[1].Definition)
+	}
+}
+
+func TestEmitCreateTableStringLiteralsSurviveColumnLevelRewrites(t *testing.T) {
+	// Literal column defaults that happen to contain table-option-looking text
+	// must reach the emitted DDL untouched -- these are table-level rewrites
+	// (ENGINE=, AFTER, COMMENT '...') and must not fire on column text.
+	statement := "create table t (" +
+		"id int, " +
+		"note varchar(32) default 'engine=innodb', " +
+		"plan varchar(64) default 'created after release', " +
+		"blurb varchar(64) default 'see comment ''foo''')"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	for _, dialect := range []struct {
+		name string
+		emit func(*CreateTable) string
+	}{
+		{"sqlite3", EmitSqlite3CreateTable},
+		{"postgres", EmitPostgresCreateTable},
+	} {
+		translated := dialect.emit(table)
+		if !strings.Contains(translated, "default 'engine=innodb'") {
+			t.Fatalf("[%s] expected engine= literal to survive, got: %q", dialect.name, translated)
+		}
+		if !strings.Contains(translated, "default 'created after release'") {
+			t.Fatalf("[%s] expected after literal to survive, got: %q", dialect.name, translated)
+		}
+		if !strings.Contains(translated, "default 'see comment ''foo'''") {
+			t.Fatalf("[%s] expected comment literal to survive, got: %q", dialect.name, translated)
+		}
+	}
+}
+
+func TestParseCreateTableIntUnsignedWeirdSpacing(t *testing.T) {
+	statement := "create table t (n int  (  11  )   unsigned)"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	translated := EmitSqlite3CreateTable(table)
+	if !strings.Contains(translated, "n int") || strings.Contains(translated, "unsigned") {
+		t.Fatalf("expected unsigned/width to be stripped, got: %q", translated)
+	}
+}
+
+func TestParseCreateTableEnumWithCommasInValues(t *testing.T) {
+	statement := "create table t (kind enum('a,b','c'))"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if len(table.Columns) != 1 {
+		t.Fatalf("expected the comma-containing enum value to stay in a single column, got %d columns", len(table.Columns))
+	}
+	translated := EmitSqlite3CreateTable(table)
+	if !strings.Contains(translated, "kind text check(kind in ('a,b','c'))") {
+		t.Fatalf("unexpected enum translation: %q", translated)
+	}
+}
+
+func TestParseAlterTableChangeColumnWithBackticks(t *testing.T) {
+	statement := "alter table `my table` change column `old` `new` int not null"
+	alter, err := ParseAlterTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if alter.Name != "my table" {
+		t.Fatalf("expected unquoted table name, got %q", alter.Name)
+	}
+	change, ok := alter.Actions[0].(ChangeColumn)
+	if !ok {
+		t.Fatalf("expected a ChangeColumn action, got %T", alter.Actions[0])
+	}
+	if change.OldName != "old" || change.New.Name != "new" {
+		t.Fatalf("unexpected change column names: %+v", change)
+	}
+}
+
+func TestParseCreateTableCommentWithAfterKeyword(t *testing.T) {
+	statement := "create table t (/* add col after release */ id int, name text)"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if len(table.Columns) != 2 {
+		t.Fatalf("expected comment to be stripped leaving 2 columns, got %d: %+v", len(table.Columns), table.Columns)
+	}
+}
+
+func TestParseAlterTableAddColumnAfter(t *testing.T) {
+	statement := "alter table t add column n int after id"
+	alter, err := ParseAlterTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	add, ok := alter.Actions[0].(AddColumn)
+	if !ok {
+		t.Fatalf("expected AddColumn action, got %T", alter.Actions[0])
+	}
+	if add.After != "id" {
+		t.Fatalf("expected After to be 'id', got %q", add.After)
+	}
+	translated := EmitSqlite3AlterTable(alter)
+	if strings.Contains(translated, "after") {
+		t.Fatalf("sqlite3 doesn't support AFTER; expected it to be dropped, got: %q", translated)
+	}
+}
+
+func TestParseAlterTableAddColumnNotNullGetsDefault(t *testing.T) {
+	statement := "alter table t add column n int not null"
+	translated := ToSqlite3Dialect(statement)
+	if !strings.Contains(translated, "not null default 0") {
+		t.Fatalf("expected a synthesized default for the NOT NULL column, got: %q", translated)
+	}
+}
+
+func TestToSqlite3DialectFallsBackOnUnparseableDDL(t *testing.T) {
+	// Legacy behavior (regex-driven) must still work for shapes the parser
+	// doesn't recognize, e.g. an ADD INDEX with an index type before its column list.
+	statement := "alter table t add index i_name using btree (name)"
+	translated := ToSqlite3Dialect(statement)
+	if !strings.Contains(translated, "create index i_name_t on t") {
+		t.Fatalf("unexpected translation: %q", translated)
+	}
+}
+
+func TestEmitCreateTableRequotesIdentifierNeedingQuoting(t *testing.T) {
+	statement := "create table `my table` (id int)"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if got := EmitSqlite3CreateTable(table); !strings.Contains(got, "create table `my table` (") {
+		t.Fatalf("expected re-quoted table name in sqlite3 output, got: %q", got)
+	}
+	if got := EmitPostgresCreateTable(table); !strings.Contains(got, `create table "my table" (`) {
+		t.Fatalf("expected re-quoted table name in postgres output, got: %q", got)
+	}
+}
+
+func TestEmitCreateTableLeavesPlainIdentifierBare(t *testing.T) {
+	statement := "create table t (id int)"
+	table, err := ParseCreateTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if got := EmitSqlite3CreateTable(table); !strings.HasPrefix(got, "create table t (") {
+		t.Fatalf("expected plain table name to stay unquoted, got: %q", got)
+	}
+	if got := EmitPostgresCreateTable(table); !strings.HasPrefix(got, "create table t (") {
+		t.Fatalf("expected plain table name to stay unquoted, got: %q", got)
+	}
+}
+
+func TestEmitAlterTableChangeColumnRequotesIdentifiersNeedingQuoting(t *testing.T) {
+	statement := "alter table `my table` change column `old col` `new col` int not null"
+	alter, err := ParseAlterTable(statement)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	got := EmitSqlite3AlterTable(alter)
+	expected := "alter table `my table` rename column `old col` to `new col`"
+	if got != expected {
+		t.Fatalf("unexpected translation:\n got: %s\nwant: %s", got, expected)
+	}
+}
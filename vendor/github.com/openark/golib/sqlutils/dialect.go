@@ -51,6 +51,36 @@ var createTableConversions = []regexpMap{
 	rmap(`(?i)add column (.* varchar.*) not null[\s]*$`, `add column $1 not null default ''`),
 }
 
+// columnTypeConversions are the subset of createTableConversions safe to run
+// against a single column's bare definition text ("name type constraints"),
+// as the AST-based emitters in ddlemit.go do. The rules excluded here
+// (charset/engine/comment/after, the ALTER-ADD-INDEX rewrites, the
+// sqlite3-skip marker) all key off surrounding statement structure rather
+// than the column itself, so applying them to one column's text risks
+// matching into an unrelated string literal in that same column -- e.g. a
+// varchar column defaulting to 'engine=innodb' would otherwise have its
+// default silently emptied out.
+var columnTypeConversions = []regexpMap{
+	rmap(`(?i)int unsigned`, `int`),
+	rmap(`(?i)int[\s]*[(][\s]*([0-9]+)[\s]*[)] unsigned`, `int`),
+	rmap(`(?i)int( not null|) auto_increment`, `integer`),
+	rmap(`(?i)([\S]+) enum[\s]*([(].*?[)])`, `$1 text check($1 in $2)`),
+	rmap(`(?i)timestamp default current_timestamp`, `timestamp default ('')`),
+	rmap(`(?i)timestamp not null default current_timestamp`, `timestamp not null default ('')`),
+}
+
+// addColumnDefaultConversions synthesizes a default for a NOT NULL column
+// added via ALTER TABLE ADD COLUMN (sqlite3/Postgres, unlike MySQL, refuse to
+// add a NOT NULL column with no default to a non-empty table). These are
+// anchored to the end of the "add column ..." clause, so unlike
+// columnTypeConversions they're safe to run over the full clause rather than
+// per-column.
+var addColumnDefaultConversions = []regexpMap{
+	rmap(`(?i)add column (.*int) not null[\s]*$`, `add column $1 not null default 0`),
+	rmap(`(?i)add column (.* text) not null[\s]*$`, `add column $1 not null default ''`),
+	rmap(`(?i)add column (.* varchar.*) not null[\s]*$`, `add column $1 not null default ''`),
+}
+
 var insertConversions = []regexpMap{
 	rmap(`(?i)insert ignore`, `insert or ignore`),
 	rmap(`(?i)now[(][)]`, `datetime('now')`),
@@ -126,6 +156,15 @@ func applyConversions(statement string, conversions []regexpMap) string {
 }
 
 func ToSqlite3CreateTable(statement string) string {
+	if IsAlterTable(statement) {
+		if alter, err := ParseAlterTable(statement); err == nil {
+			return EmitSqlite3AlterTable(alter)
+		}
+		return applyConversions(statement, createTableConversions)
+	}
+	if table, err := ParseCreateTable(statement); err == nil {
+		return EmitSqlite3CreateTable(table)
+	}
 	return applyConversions(statement, createTableConversions)
 }
 
@@ -134,6 +173,10 @@ func ToSqlite3Insert(statement string) string {
 }
 
 func ToSqlite3Dialect(statement string) (translated string) {
+	return cachedTranslate(DialectSqlite3, statement, toSqlite3DialectUncached)
+}
+
+func toSqlite3DialectUncached(statement string) (translated string) {
 	if IsCreateTable(statement) {
 		return ToSqlite3CreateTable(statement)
 	}
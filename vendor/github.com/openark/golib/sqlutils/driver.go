@@ -0,0 +1,89 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SqliteOptions holds the bootstrap knobs that both sqlite drivers need to
+// apply, independent of which concrete driver opens the connection.
+type SqliteOptions struct {
+	JournalMode   string // e.g. "WAL"; empty defaults to "WAL"
+	BusyTimeoutMs int    // 0 defaults to 5000
+	ForeignKeys   bool
+}
+
+// DefaultSqliteOptions returns the settings orchestrator has historically
+// relied on: WAL journaling, a 5s busy timeout, and foreign keys enabled.
+func DefaultSqliteOptions() SqliteOptions {
+	return SqliteOptions{JournalMode: "WAL", BusyTimeoutMs: 5000, ForeignKeys: true}
+}
+
+// SqliteDriver abstracts the differences between the cgo mattn/go-sqlite3
+// driver and a pure-Go driver (modernc.org/sqlite, or a libSQL driver), so
+// callers can be cross-compiled without cgo by swapping implementations via
+// the sqlutils_puresqlite build tag rather than branching on call sites.
+type SqliteDriver interface {
+	// DriverName is the name registered with database/sql, e.g. "sqlite3".
+	DriverName() string
+	// DSN builds the data source name/connection string for the database at path.
+	DSN(path string, opts SqliteOptions) string
+	// Bootstrap applies the startup PRAGMAs appropriate for this driver.
+	Bootstrap(db *sql.DB, opts SqliteOptions) error
+}
+
+// OpenSqlite opens the database at path via driver and applies opts.
+func OpenSqlite(driver SqliteDriver, path string, opts SqliteOptions) (*sql.DB, error) {
+	db, err := sql.Open(driver.DriverName(), driver.DSN(path, opts))
+	if err != nil {
+		return nil, err
+	}
+	if err := driver.Bootstrap(db, opts); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// bootstrapPragmas runs the PRAGMA statements shared by both drivers; each
+// driver's Bootstrap calls this after any driver-specific setup.
+func bootstrapPragmas(db *sql.DB, opts SqliteOptions) error {
+	journalMode := opts.JournalMode
+	if journalMode == "" {
+		journalMode = "WAL"
+	}
+	busyTimeoutMs := opts.BusyTimeoutMs
+	if busyTimeoutMs == 0 {
+		busyTimeoutMs = 5000
+	}
+
+	pragmas := []string{
+		fmt.Sprintf("PRAGMA journal_mode=%s", journalMode),
+		fmt.Sprintf("PRAGMA busy_timeout=%d", busyTimeoutMs),
+	}
+	if opts.ForeignKeys {
+		pragmas = append(pragmas, "PRAGMA foreign_keys=ON")
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			return fmt.Errorf("sqlutils: bootstrap pragma %q: %w", pragma, err)
+		}
+	}
+	return nil
+}
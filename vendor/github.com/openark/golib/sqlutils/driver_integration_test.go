@@ -0,0 +1,82 @@
+//go:build sqlite_integration
+
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"strings"
+	"testing"
+)
+
+// splitAlterStatements splits the semicolon-joined output of
+// EmitSqlite3AlterTable back into individual statements to exec one at a time.
+func splitAlterStatements(s string) []string {
+	return strings.Split(s, "; ")
+}
+
+// orchestratorSchemaMigrations mirrors a handful of orchestrator's actual
+// metadata-schema migrations, enough to exercise CREATE TABLE translation,
+// ALTER TABLE ADD COLUMN, and an index creation against a live driver.
+var orchestratorSchemaMigrations = []string{
+	`create table database_instance (
+		hostname varchar(128) not null,
+		port smallint not null,
+		last_checked timestamp not null default current_timestamp,
+		primary key (hostname, port)
+	) engine=innodb`,
+	`alter table database_instance add column data_center varchar(32) not null after port`,
+	`alter table database_instance add index ix_last_checked (last_checked)`,
+}
+
+// TestSchemaMigrationsRunOnDefaultDriver proves the active SqliteDriver
+// (selected by the sqlutils_puresqlite build tag) can bootstrap and apply
+// orchestrator's schema migrations end to end. Run against both drivers with:
+//
+//	go test -tags sqlite_integration ./...
+//	go test -tags "sqlite_integration sqlutils_puresqlite" ./...
+func TestSchemaMigrationsRunOnDefaultDriver(t *testing.T) {
+	db, err := OpenSqlite(DefaultSqliteDriver, ":memory:", DefaultSqliteOptions())
+	if err != nil {
+		t.Fatalf("OpenSqlite: %v", err)
+	}
+	defer db.Close()
+
+	for _, migration := range orchestratorSchemaMigrations {
+		translated := ToSqlite3Dialect(migration)
+		for _, stmt := range splitAlterStatements(translated) {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("exec %q: %v", stmt, err)
+			}
+		}
+	}
+
+	if _, err := db.Exec(
+		`insert into database_instance (hostname, port, data_center) values (?, ?, ?)`,
+		"host1", 3306, "dc1",
+	); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`select count(*) from database_instance`).Scan(&count); err != nil {
+		t.Fatalf("select count: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 row, got %d", count)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build sqlutils_puresqlite
+
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// pureSqliteDriver is the SqliteDriver used when built with the
+// sqlutils_puresqlite tag, so orchestrator can be cross-compiled without
+// cgo for container/edge deployments. It is backed by modernc.org/sqlite.
+type pureSqliteDriver struct{}
+
+// DefaultSqliteDriver is the SqliteDriver orchestrator uses when built with
+// the sqlutils_puresqlite tag.
+var DefaultSqliteDriver SqliteDriver = pureSqliteDriver{}
+
+func (pureSqliteDriver) DriverName() string { return "sqlite" }
+
+func (pureSqliteDriver) DSN(path string, opts SqliteOptions) string {
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(%s)", path, boolParam(opts.ForeignKeys))
+}
+
+func (pureSqliteDriver) Bootstrap(db *sql.DB, opts SqliteOptions) error {
+	return bootstrapPragmas(db, opts)
+}
+
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,202 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Typed errors callers can compare against with errors.Is, regardless of which
+// driver (mysql, sqlite3, postgres) produced the underlying failure.
+var (
+	ErrDuplicateKey         = errors.New("sqlutils: duplicate key")
+	ErrDeadlock             = errors.New("sqlutils: deadlock detected")
+	ErrLockWaitTimeout      = errors.New("sqlutils: lock wait timeout exceeded")
+	ErrSerializationFailure = errors.New("sqlutils: serialization failure")
+	ErrCheckConstraint      = errors.New("sqlutils: check constraint violation")
+)
+
+// translatedError wraps a driver error with one of the typed errors above,
+// so callers can both errors.Is() against the typed error and errors.Unwrap()
+// down to the original driver error if they need the raw message.
+type translatedError struct {
+	typed    error
+	original error
+}
+
+func (e *translatedError) Error() string        { return e.typed.Error() + ": " + e.original.Error() }
+func (e *translatedError) Unwrap() error        { return e.original }
+func (e *translatedError) Is(target error) bool { return target == e.typed }
+
+// ErrorTranslator converts a driver-specific error into one of the typed
+// errors above. It returns the error unchanged if it doesn't recognize it.
+type ErrorTranslator interface {
+	TranslateError(err error) error
+}
+
+// mysqlErrorTranslator recognizes github.com/go-sql-driver/mysql.MySQLError
+// by duck-typing its `Number uint16` field, so this package needn't import
+// the driver.
+type mysqlErrorTranslator struct{}
+
+const (
+	mysqlErrDupEntry        = 1062
+	mysqlErrLockDeadlock    = 1213
+	mysqlErrLockWaitTimeout = 1205
+)
+
+func (mysqlErrorTranslator) TranslateError(err error) error {
+	number, ok := driverErrorField(err, "Number")
+	if !ok {
+		return err
+	}
+	switch number {
+	case mysqlErrDupEntry:
+		return &translatedError{ErrDuplicateKey, err}
+	case mysqlErrLockDeadlock:
+		return &translatedError{ErrDeadlock, err}
+	case mysqlErrLockWaitTimeout:
+		return &translatedError{ErrLockWaitTimeout, err}
+	}
+	return err
+}
+
+// sqlite3ErrorTranslator recognizes github.com/mattn/go-sqlite3.Error by
+// duck-typing its `ExtendedCode int` field.
+type sqlite3ErrorTranslator struct{}
+
+const (
+	sqlite3ExtendedCodeConstraintUnique     = 2067
+	sqlite3ExtendedCodeConstraintPrimaryKey = 1555
+	sqlite3ExtendedCodeConstraintCheck      = 275
+)
+
+func (sqlite3ErrorTranslator) TranslateError(err error) error {
+	code, ok := driverErrorField(err, "ExtendedCode")
+	if !ok {
+		return err
+	}
+	switch code {
+	case sqlite3ExtendedCodeConstraintUnique, sqlite3ExtendedCodeConstraintPrimaryKey:
+		return &translatedError{ErrDuplicateKey, err}
+	case sqlite3ExtendedCodeConstraintCheck:
+		return &translatedError{ErrCheckConstraint, err}
+	}
+	return err
+}
+
+// postgresErrorTranslator recognizes github.com/lib/pq.Error by duck-typing
+// its `Code pq.ErrorCode` field, which stringifies to the SQLSTATE code.
+type postgresErrorTranslator struct{}
+
+const (
+	postgresSqlstateUniqueViolation      = "23505"
+	postgresSqlstateSerializationFailure = "40001"
+	postgresSqlstateDeadlockDetected     = "40P01"
+	postgresSqlstateCheckViolation       = "23514"
+	postgresSqlstateLockNotAvailable     = "55P03"
+)
+
+func (postgresErrorTranslator) TranslateError(err error) error {
+	code, ok := driverErrorFieldString(err, "Code")
+	if !ok {
+		return err
+	}
+	switch code {
+	case postgresSqlstateUniqueViolation:
+		return &translatedError{ErrDuplicateKey, err}
+	case postgresSqlstateSerializationFailure:
+		return &translatedError{ErrSerializationFailure, err}
+	case postgresSqlstateDeadlockDetected:
+		return &translatedError{ErrDeadlock, err}
+	case postgresSqlstateCheckViolation:
+		return &translatedError{ErrCheckConstraint, err}
+	case postgresSqlstateLockNotAvailable:
+		return &translatedError{ErrLockWaitTimeout, err}
+	}
+	return err
+}
+
+// errorTranslatorForDialect returns the ErrorTranslator matching dialect.
+func errorTranslatorForDialect(dialect Dialect) ErrorTranslator {
+	switch dialect {
+	case DialectPostgres:
+		return postgresErrorTranslator{}
+	case DialectMySQL:
+		return mysqlErrorTranslator{}
+	default:
+		return sqlite3ErrorTranslator{}
+	}
+}
+
+// TranslateError converts a driver error produced while talking to the given
+// dialect into one of this package's typed errors (ErrDuplicateKey,
+// ErrDeadlock, ...), or returns it unchanged if unrecognized.
+func TranslateError(dialect Dialect, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errorTranslatorForDialect(dialect).TranslateError(err)
+}
+
+// driverErrorField reads a numeric field (uint16, int, etc.) off err by name
+// via reflection, so this package can classify mysql/sqlite3 errors without
+// vendoring those drivers.
+func driverErrorField(err error, field string) (int64, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	f := v.FieldByName(field)
+	if !f.IsValid() {
+		return 0, false
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
+
+// driverErrorFieldString reads a field off err by name and returns its
+// string form (via fmt.Stringer if available, else the underlying string kind).
+func driverErrorFieldString(err error, field string) (string, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	f := v.FieldByName(field)
+	if !f.IsValid() {
+		return "", false
+	}
+	if stringer, ok := f.Interface().(interface{ String() string }); ok {
+		return stringer.String(), true
+	}
+	if f.Kind() == reflect.String {
+		return f.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeMySQLError mimics github.com/go-sql-driver/mysql.MySQLError's shape
+// without depending on the driver.
+type fakeMySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *fakeMySQLError) Error() string { return e.Message }
+
+// fakeSqlite3Error mimics github.com/mattn/go-sqlite3.Error's shape.
+type fakeSqlite3Error struct {
+	ExtendedCode int
+}
+
+func (e *fakeSqlite3Error) Error() string { return "sqlite3 error" }
+
+// fakePqErrorCode mimics pq.ErrorCode's Stringer-based string form.
+type fakePqErrorCode string
+
+func (c fakePqErrorCode) String() string { return string(c) }
+
+// fakePqError mimics github.com/lib/pq.Error's shape.
+type fakePqError struct {
+	Code fakePqErrorCode
+}
+
+func (e *fakePqError) Error() string { return "pq error" }
+
+func TestTranslateErrorMySQLDuplicateKey(t *testing.T) {
+	err := &fakeMySQLError{Number: 1062, Message: "Duplicate entry"}
+	translated := TranslateError(DialectMySQL, err)
+	if !errors.Is(translated, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", translated)
+	}
+	if !errors.Is(translated, err) {
+		t.Fatalf("expected translated error to unwrap to original, got %v", translated)
+	}
+}
+
+func TestTranslateErrorSqlite3PrimaryKeyConstraintIsDuplicateKey(t *testing.T) {
+	// SQLITE_CONSTRAINT_PRIMARYKEY (1555), not a check constraint.
+	err := &fakeSqlite3Error{ExtendedCode: 1555}
+	translated := TranslateError(DialectSqlite3, err)
+	if !errors.Is(translated, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", translated)
+	}
+}
+
+func TestTranslateErrorSqlite3CheckConstraint(t *testing.T) {
+	// SQLITE_CONSTRAINT_CHECK (275).
+	err := &fakeSqlite3Error{ExtendedCode: 275}
+	translated := TranslateError(DialectSqlite3, err)
+	if !errors.Is(translated, ErrCheckConstraint) {
+		t.Fatalf("expected ErrCheckConstraint, got %v", translated)
+	}
+}
+
+func TestTranslateErrorPostgresSerializationFailure(t *testing.T) {
+	err := &fakePqError{Code: "40001"}
+	translated := TranslateError(DialectPostgres, err)
+	if !errors.Is(translated, ErrSerializationFailure) {
+		t.Fatalf("expected ErrSerializationFailure, got %v", translated)
+	}
+}
+
+func TestTranslateErrorUnrecognizedPassesThrough(t *testing.T) {
+	err := errors.New("some other failure")
+	if translated := TranslateError(DialectMySQL, err); translated != err {
+		t.Fatalf("expected unrecognized error to pass through unchanged, got %v", translated)
+	}
+}
@@ -0,0 +1,48 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import "database/sql"
+
+// ExecNoPrepare executes query against db without preparing it first, the
+// way orchestrator's topology writer issues one-off INSERT/UPDATE
+// statements. Any driver error is run through TranslateError for dialect, so
+// callers can distinguish e.g. an already-recorded row (ErrDuplicateKey)
+// from a real failure without string-matching the error message.
+func ExecNoPrepare(dialect Dialect, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return result, TranslateError(dialect, err)
+	}
+	return result, nil
+}
+
+// Exec prepares query before executing it against db, translating any
+// driver error for dialect the same way ExecNoPrepare does.
+func Exec(dialect Dialect, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, TranslateError(dialect, err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return result, TranslateError(dialect, err)
+	}
+	return result, nil
+}
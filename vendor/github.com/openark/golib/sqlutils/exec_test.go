@@ -0,0 +1,87 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeFailingDriver is a minimal database/sql/driver.Driver whose every
+// Exec (prepared or not) fails with a canned mysql-shaped duplicate-key
+// error, so ExecNoPrepare/Exec can be tested without a real mysql/sqlite3/
+// postgres driver vendored.
+type fakeFailingDriver struct{}
+
+func (fakeFailingDriver) Open(name string) (driver.Conn, error) { return fakeFailingConn{}, nil }
+
+type fakeFailingConn struct{}
+
+func (fakeFailingConn) Prepare(query string) (driver.Stmt, error) { return fakeFailingStmt{}, nil }
+func (fakeFailingConn) Close() error                              { return nil }
+func (fakeFailingConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+// Exec implements the legacy driver.Execer interface so db.Exec doesn't need
+// to go through Prepare for the ExecNoPrepare test.
+func (fakeFailingConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	return nil, &fakeMySQLError{Number: 1062, Message: "Duplicate entry"}
+}
+
+type fakeFailingStmt struct{}
+
+func (fakeFailingStmt) Close() error  { return nil }
+func (fakeFailingStmt) NumInput() int { return -1 }
+func (fakeFailingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, &fakeMySQLError{Number: 1062, Message: "Duplicate entry"}
+}
+func (fakeFailingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var registerFakeFailingDriverOnce sync.Once
+
+func openFakeFailingDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerFakeFailingDriverOnce.Do(func() {
+		sql.Register("sqlutils-fake-failing", fakeFailingDriver{})
+	})
+	db, err := sql.Open("sqlutils-fake-failing", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestExecNoPrepareTranslatesDriverError(t *testing.T) {
+	db := openFakeFailingDB(t)
+	_, err := ExecNoPrepare(DialectMySQL, db, "insert into t (a) values (?)", 1)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func TestExecTranslatesDriverError(t *testing.T) {
+	db := openFakeFailingDB(t)
+	_, err := Exec(DialectMySQL, db, "insert into t (a) values (?)", 1)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+}
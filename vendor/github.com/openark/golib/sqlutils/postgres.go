@@ -0,0 +1,303 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Dialect identifies the target SQL backend a statement is being translated into.
+type Dialect int
+
+const (
+	DialectSqlite3 Dialect = iota
+	DialectPostgres
+	DialectMySQL
+)
+
+var postgresCreateTableConversions = []regexpMap{
+	rmap(`(?i) (character set|charset) [\S]+`, ``),
+	rmap(`(?i)engine[\s]*=[\s]*(innodb|myisam|ndb|memory|tokudb)`, ``),
+	rmap(`(?i)DEFAULT CHARSET[\s]*=[\s]*[\S]+`, ``),
+	rmap(`(?i)comment '[^']*'`, ``),
+	rmap(`(?i)after [\S]+`, ``),
+	rmap("`([^`]+)`", `"$1"`),
+
+	rmap(`(?i)bigint([\s]*[(][\s]*[0-9]+[\s]*[)]|)( unsigned|) auto_increment`, `bigserial`),
+	rmap(`(?i)int([\s]*[(][\s]*[0-9]+[\s]*[)]|)( unsigned|) auto_increment`, `serial`),
+
+	rmap(`(?i)int[\s]*[(][\s]*([0-9]+)[\s]*[)] unsigned`, `integer`),
+	rmap(`(?i)int unsigned`, `integer`),
+	rmap(`(?i)tinyint[\s]*[(][\s]*[0-9]+[\s]*[)]`, `smallint`),
+	rmap(`(?i)bigint[\s]*[(][\s]*[0-9]+[\s]*[)]`, `bigint`),
+	rmap(`(?i)int[\s]*[(][\s]*[0-9]+[\s]*[)]`, `integer`),
+
+	rmap(`(?i)alter table ([\S]+) add (index|key) ([\S]+) (.+)`, `create index ${3}_${1} on $1 $4`),
+	rmap(`(?i)alter table ([\S]+) add unique (index|key) ([\S]+) (.+)`, `create unique index ${3}_${1} on $1 $4`),
+
+	rmap(`(?i)([\S]+) enum[\s]*([(].*?[)])`, `$1 text check($1 in $2)`),
+	rmap(`(?i)([\s\S]+[/][*] sqlite3-skip [*][/][\s\S]+)`, ``),
+
+	rmap(`(?i)timestamp not null default current_timestamp`, `timestamp not null default now()`),
+	rmap(`(?i)timestamp default current_timestamp`, `timestamp default now()`),
+
+	rmap(`(?i)add column (.*int) not null[\s]*$`, `add column $1 not null default 0`),
+	rmap(`(?i)add column (.* text) not null[\s]*$`, `add column $1 not null default ''`),
+	rmap(`(?i)add column (.* varchar.*) not null[\s]*$`, `add column $1 not null default ''`),
+}
+
+var postgresInsertConversions = []regexpMap{
+	rmap(`(?i)insert ignore into ([\s\S]+)`, `insert into $1 on conflict do nothing`),
+}
+
+var identifyOnDuplicateKeyUpdate = regexp.MustCompile(`(?i)\son\s+duplicate\s+key\s+update\s`)
+
+var (
+	conflictKeysMu sync.RWMutex
+	conflictKeys   = map[string][]string{}
+)
+
+// RegisterConflictKey records the primary/unique key columns MySQL's
+// `ON DUPLICATE KEY UPDATE` relies on implicitly for table, so that
+// translating an INSERT against it into Postgres can emit a valid
+// `ON CONFLICT (key cols) DO UPDATE` target. MySQL leaves the conflicting
+// key unstated in the statement itself (it's whichever unique/primary key
+// the row collided on), but Postgres requires it spelled out, so callers
+// must register it once per table (typically right after issuing its
+// CREATE TABLE) before translating that table's upsert statements.
+func RegisterConflictKey(table string, columns ...string) {
+	conflictKeysMu.Lock()
+	defer conflictKeysMu.Unlock()
+	conflictKeys[table] = append([]string(nil), columns...)
+}
+
+func lookupConflictKey(table string) ([]string, bool) {
+	conflictKeysMu.RLock()
+	defer conflictKeysMu.RUnlock()
+	columns, ok := conflictKeys[table]
+	return columns, ok
+}
+
+// convertOnDuplicateKeyUpdate rewrites a MySQL
+// `INSERT INTO t (cols) VALUES (...) ON DUPLICATE KEY UPDATE a = VALUES(a), ...`
+// into Postgres' `INSERT INTO t (cols) VALUES (...) ON CONFLICT (key cols) DO
+// UPDATE SET a = excluded.a, ...`, using the key columns t was registered
+// with via RegisterConflictKey. It leaves the statement untouched if t has no
+// registered key, rather than guessing a target that might not match any
+// actual constraint (guessing is worse than leaving the statement for the
+// caller to notice untranslated: a wrong conflict target fails at runtime
+// with a Postgres error naming the exact constraint it expected, whereas a
+// guessed-but-plausible one can silently upsert against the wrong key).
+func convertOnDuplicateKeyUpdate(statement string) (string, bool) {
+	loc := identifyOnDuplicateKeyUpdate.FindStringIndex(statement)
+	if loc == nil {
+		return statement, false
+	}
+	head := strings.TrimSpace(statement[:loc[0]])
+	assignments := strings.TrimSpace(statement[loc[1]:])
+
+	if !hasPrefixFold(head, "insert into") {
+		return statement, false
+	}
+	rest := strings.TrimSpace(head[len("insert into"):])
+	table, rest := firstToken(rest)
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") {
+		return statement, false
+	}
+	conflictColumns, ok := lookupConflictKey(unquoteIdent(table))
+	if !ok {
+		return statement, false
+	}
+	runes := []rune(rest)
+	colsClose := findMatchingParen(runes, 0)
+	if colsClose < 0 {
+		return statement, false
+	}
+	columnsPart := string(runes[1:colsClose])
+	valuesPart := strings.TrimSpace(string(runes[colsClose+1:]))
+
+	assignmentParts := splitTopLevel(assignments)
+	rewritten := make([]string, 0, len(assignmentParts))
+	for _, part := range assignmentParts {
+		eq := strings.IndexByte(part, '=')
+		if eq < 0 {
+			continue
+		}
+		col := unquoteIdent(strings.TrimSpace(part[:eq]))
+		expr := strings.TrimSpace(part[eq+1:])
+		expr = excludedValuesPattern.ReplaceAllString(expr, "excluded.$1")
+		rewritten = append(rewritten, fmt.Sprintf("%s = %s", col, expr))
+	}
+
+	return fmt.Sprintf("insert into %s (%s) %s on conflict (%s) do update set %s",
+		table, columnsPart, valuesPart, strings.Join(conflictColumns, ", "), strings.Join(rewritten, ", ")), true
+}
+
+var excludedValuesPattern = regexp.MustCompile(`(?i)values\s*\(\s*([\S]+)\s*\)`)
+
+var postgresGeneralConversions = []regexpMap{
+	rmap(`(?i)unix_timestamp[(]([^)]+)[)]`, `extract(epoch from $1)::bigint`),
+	rmap(`(?i)unix_timestamp[(][)]`, `extract(epoch from now())::bigint`),
+
+	rmap(`(?i)now[(][)][\s]*[-][\s]*interval [?] ([\w]+)`, `now() - (? || ' $1')::interval`),
+	rmap(`(?i)now[(][)][\s]*[+][\s]*interval [?] ([\w]+)`, `now() + (? || ' $1')::interval`),
+
+	rmap("`([^`]+)`", `"$1"`),
+
+	rmap(`(?i)\bconcat[(][\s]*([^,)]+)[\s]*,[\s]*([^,)]+)[\s]*[)]`, `($1 || $2)`),
+	rmap(`(?i)\bconcat[(][\s]*([^,)]+)[\s]*,[\s]*([^,)]+)[\s]*,[\s]*([^,)]+)[\s]*[)]`, `($1 || $2 || $3)`),
+
+	rmap(`(?i) rlike `, ` ~ `),
+}
+
+func ToPostgresCreateTable(statement string) string {
+	if IsAlterTable(statement) {
+		if alter, err := ParseAlterTable(statement); err == nil {
+			return EmitPostgresAlterTable(alter)
+		}
+		return applyConversions(statement, postgresCreateTableConversions)
+	}
+	if table, err := ParseCreateTable(statement); err == nil {
+		return EmitPostgresCreateTable(table)
+	}
+	return applyConversions(statement, postgresCreateTableConversions)
+}
+
+// postgresColumnTypeConversions is the subset of postgresCreateTableConversions
+// safe to run against a single column's bare definition text, for the same
+// reason columnTypeConversions exists for sqlite3: the excluded rules
+// (charset/engine/comment/after, the ALTER-ADD-INDEX rewrites, the
+// sqlite3-skip marker) key off surrounding statement structure and can match
+// into an unrelated string literal within the column itself.
+var postgresColumnTypeConversions = []regexpMap{
+	rmap("`([^`]+)`", `"$1"`),
+
+	rmap(`(?i)bigint([\s]*[(][\s]*[0-9]+[\s]*[)]|)( unsigned|) auto_increment`, `bigserial`),
+	rmap(`(?i)int([\s]*[(][\s]*[0-9]+[\s]*[)]|)( unsigned|) auto_increment`, `serial`),
+
+	rmap(`(?i)int[\s]*[(][\s]*([0-9]+)[\s]*[)] unsigned`, `integer`),
+	rmap(`(?i)int unsigned`, `integer`),
+	rmap(`(?i)tinyint[\s]*[(][\s]*[0-9]+[\s]*[)]`, `smallint`),
+	rmap(`(?i)bigint[\s]*[(][\s]*[0-9]+[\s]*[)]`, `bigint`),
+	rmap(`(?i)int[\s]*[(][\s]*[0-9]+[\s]*[)]`, `integer`),
+
+	rmap(`(?i)([\S]+) enum[\s]*([(].*?[)])`, `$1 text check($1 in $2)`),
+
+	rmap(`(?i)timestamp not null default current_timestamp`, `timestamp not null default now()`),
+	rmap(`(?i)timestamp default current_timestamp`, `timestamp default now()`),
+}
+
+// postgresAddColumnDefaultConversions synthesizes a default for a NOT NULL
+// column added via ALTER TABLE ADD COLUMN, the Postgres counterpart to
+// addColumnDefaultConversions. Anchored to the end of the clause, so safe to
+// run over the full "add column ..." clause rather than per-column.
+var postgresAddColumnDefaultConversions = []regexpMap{
+	rmap(`(?i)add column (.*int) not null[\s]*$`, `add column $1 not null default 0`),
+	rmap(`(?i)add column (.* text) not null[\s]*$`, `add column $1 not null default ''`),
+	rmap(`(?i)add column (.* varchar.*) not null[\s]*$`, `add column $1 not null default ''`),
+}
+
+// EmitPostgresCreateTable renders a parsed CreateTable as Postgres DDL.
+func EmitPostgresCreateTable(t *CreateTable) string {
+	cols := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		def := applyConversions(c.Name+" "+c.Definition, postgresColumnTypeConversions)
+		cols = append(cols, strings.TrimSpace(def))
+	}
+	options := strings.TrimSpace(applyConversions(t.Options, postgresCreateTableConversions))
+	stmt := fmt.Sprintf("create table %s (%s)", quotePostgresIdent(t.Name), strings.Join(cols, ", "))
+	if options != "" {
+		stmt += " " + options
+	}
+	return stmt
+}
+
+// EmitPostgresAlterTable renders a parsed AlterTable as one or more
+// semicolon-joined Postgres statements.
+func EmitPostgresAlterTable(t *AlterTable) string {
+	var stmts []string
+	for _, action := range t.Actions {
+		switch a := action.(type) {
+		case AddColumn:
+			// Run conversions over the full "add column ..." clause, not just
+			// the column definition, so the postgresAddColumnDefaultConversions
+			// rules (anchored to the end of the clause) still match.
+			clause := fmt.Sprintf("add column %s %s", a.Column.Name, a.Column.Definition)
+			clause = applyConversions(clause, postgresColumnTypeConversions)
+			clause = strings.TrimSpace(applyConversions(clause, postgresAddColumnDefaultConversions))
+			stmts = append(stmts, fmt.Sprintf("alter table %s %s", quotePostgresIdent(t.Name), clause))
+		case AddIndex:
+			unique := ""
+			if a.Unique {
+				unique = "unique "
+			}
+			table := quotePostgresIdent(t.Name)
+			stmts = append(stmts, fmt.Sprintf("create %sindex %s_%s on %s %s", unique, a.Name, t.Name, table, a.Columns))
+		case DropIndex:
+			stmts = append(stmts, fmt.Sprintf("drop index %s", a.Name))
+		case ChangeColumn:
+			stmts = append(stmts, fmt.Sprintf("alter table %s rename column %s to %s",
+				quotePostgresIdent(t.Name), quotePostgresIdent(a.OldName), quotePostgresIdent(a.New.Name)))
+		case RenameTable:
+			stmts = append(stmts, fmt.Sprintf("alter table %s rename to %s", quotePostgresIdent(t.Name), quotePostgresIdent(a.NewName)))
+		}
+	}
+	return strings.Join(stmts, "; ")
+}
+
+func ToPostgresInsert(statement string) string {
+	if converted, ok := convertOnDuplicateKeyUpdate(statement); ok {
+		return converted
+	}
+	return applyConversions(statement, postgresInsertConversions)
+}
+
+func ToPostgresDialect(statement string) (translated string) {
+	return cachedTranslate(DialectPostgres, statement, toPostgresDialectUncached)
+}
+
+func toPostgresDialectUncached(statement string) (translated string) {
+	if IsCreateTable(statement) {
+		return ToPostgresCreateTable(statement)
+	}
+	if IsAlterTable(statement) {
+		return ToPostgresCreateTable(statement)
+	}
+	statement = applyConversions(statement, postgresGeneralConversions)
+	if IsInsert(statement) {
+		return ToPostgresInsert(statement)
+	}
+	return statement
+}
+
+// ToDialect translates statement for the given target dialect, routing to the
+// matching sqlite3/postgres pipeline. Statements are written in MySQL, so
+// DialectMySQL returns statement unchanged.
+func ToDialect(dialect Dialect, statement string) string {
+	switch dialect {
+	case DialectPostgres:
+		return ToPostgresDialect(statement)
+	case DialectMySQL:
+		return statement
+	default:
+		return ToSqlite3Dialect(statement)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+   Copyright 2017 GitHub Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sqlutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToPostgresDialectAddColumnNotNullGetsDefault(t *testing.T) {
+	statement := "alter table t add column n int not null"
+	translated := ToPostgresDialect(statement)
+	if !strings.Contains(translated, "not null default 0") {
+		t.Fatalf("expected a synthesized default for the NOT NULL column, got: %q", translated)
+	}
+}
+
+func TestToPostgresDialectOnDuplicateKeyUpdate(t *testing.T) {
+	RegisterConflictKey("node_health", "hostname", "token")
+	statement := "insert into node_health (hostname, token, last_seen_active) values (?, ?, now()) on duplicate key update last_seen_active = values(last_seen_active)"
+	expected := "insert into node_health (hostname, token, last_seen_active) values (?, ?, now()) on conflict (hostname, token) do update set last_seen_active = excluded.last_seen_active"
+	if got := ToPostgresDialect(statement); got != expected {
+		t.Fatalf("unexpected translation:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestToPostgresDialectOnDuplicateKeyUpdateMultipleAssignments(t *testing.T) {
+	RegisterConflictKey("database_instance_maintenance", "hostname", "port")
+	statement := "insert into database_instance_maintenance (hostname, port, begin_timestamp) values (?, ?, now()) on duplicate key update begin_timestamp = values(begin_timestamp), owner = values(owner)"
+	translated := ToPostgresDialect(statement)
+	if !strings.Contains(translated, "on conflict (hostname, port) do update set") {
+		t.Fatalf("expected registered key as conflict target, got: %s", translated)
+	}
+	if !strings.Contains(translated, "begin_timestamp = excluded.begin_timestamp") || !strings.Contains(translated, "owner = excluded.owner") {
+		t.Fatalf("expected VALUES() refs rewritten to excluded., got: %s", translated)
+	}
+}
+
+func TestToPostgresDialectOnDuplicateKeyUpdateEvenWhenKeyColumnItselfIsUpdated(t *testing.T) {
+	// A key column can legitimately appear in the UPDATE clause (e.g. to
+	// refresh its case or canonical form on conflict); the conflict target
+	// must still be the registered key, not "insert columns minus updated
+	// columns".
+	RegisterConflictKey("hostname_resolve", "hostname")
+	statement := "insert into hostname_resolve (hostname, resolved_hostname) values (?, ?) on duplicate key update hostname = values(hostname), resolved_hostname = values(resolved_hostname)"
+	expected := "insert into hostname_resolve (hostname, resolved_hostname) values (?, ?) on conflict (hostname) do update set hostname = excluded.hostname, resolved_hostname = excluded.resolved_hostname"
+	if got := ToPostgresDialect(statement); got != expected {
+		t.Fatalf("unexpected translation:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestToPostgresDialectOnDuplicateKeyUpdateWithoutRegisteredKeyIsLeftUntranslated(t *testing.T) {
+	statement := "insert into unregistered_table (a, b) values (?, ?) on duplicate key update b = values(b)"
+	if got := ToPostgresDialect(statement); got != statement {
+		t.Fatalf("expected statement to pass through unchanged without a registered conflict key, got: %s", got)
+	}
+}